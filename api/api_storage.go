@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-address"
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// StorageMiner is the low-level interface exposed by a lotus storage miner
+// node's JSON-RPC API. lotus-storage-miner and other operator tooling talk
+// to a running miner exclusively through this interface.
+type StorageMiner interface {
+	// ActorAddress returns the address of the miner actor this node operates.
+	ActorAddress(ctx context.Context) (address.Address, error)
+
+	// ActorSectorSize returns the sector size configured for the given miner actor.
+	ActorSectorSize(ctx context.Context, addr address.Address) (abi.SectorSize, error)
+
+	// MarketSetAsk configures the miner's storage ask.
+	MarketSetAsk(ctx context.Context, price types.BigInt, blocksDuration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error
+
+	// MarketGetAsk returns the miner's current storage ask, or nil if none is set.
+	MarketGetAsk(ctx context.Context) (*storagemarket.SignedStorageAsk, error)
+
+	// MarketSetRetrievalAsk configures the miner's retrieval ask.
+	MarketSetRetrievalAsk(ctx context.Context, rask *retrievalmarket.Ask) error
+
+	// MarketGetRetrievalAsk returns the miner's current retrieval ask, or nil if none is set.
+	MarketGetRetrievalAsk(ctx context.Context) (*retrievalmarket.Ask, error)
+
+	// DealsSetAcceptingStorageDeals configures whether the miner considers new storage deal proposals.
+	DealsSetAcceptingStorageDeals(ctx context.Context, b bool) error
+
+	// DealsImportData manually imports data for a storage deal that has already been proposed.
+	DealsImportData(ctx context.Context, dealProposalCid cid.Cid, file string) error
+
+	// MarketListIncompleteDeals lists every deal tracked by the miner's storage provider; deals that have reached a
+	// terminal state are included.
+	MarketListIncompleteDeals(ctx context.Context) ([]storagemarket.MinerDeal, error)
+
+	// MarketListDataTransfers lists the data-transfer channels backing the miner's storage and retrieval deals.
+	MarketListDataTransfers(ctx context.Context) ([]DataTransferChannel, error)
+
+	// MarketDataTransferUpdates returns a channel that receives an update when a data-transfer channel changes state.
+	// Updates that arrive while the channel's buffer is full are dropped.
+	MarketDataTransferUpdates(ctx context.Context) (<-chan DataTransferChannel, error)
+
+	// MarketRestartDataTransfer attempts to restart a stalled data-transfer channel.
+	MarketRestartDataTransfer(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error
+
+	// MarketCancelDataTransfer cancels an in-progress data-transfer channel.
+	MarketCancelDataTransfer(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error
+
+	// MarketSetPieceCidBlocklist replaces the set of piece CIDs the miner will refuse to accept storage deals for.
+	MarketSetPieceCidBlocklist(ctx context.Context, blocklist []cid.Cid) error
+
+	// MarketSetClientAllowlist replaces the set of clients the miner will accept storage deals from. An empty list allows any client.
+	MarketSetClientAllowlist(ctx context.Context, allowlist []peer.ID) error
+
+	// MarketSetClientPriceOverride sets a per-client override for the miner's base storage ask price. A zero price clears the override.
+	MarketSetClientPriceOverride(ctx context.Context, client peer.ID, price types.BigInt) error
+
+	// MarketGetDealUpdates returns the buffered lifecycle history for a single deal, identified by its proposal CID, oldest first.
+	MarketGetDealUpdates(ctx context.Context, proposalCid cid.Cid) ([]MarketDealEvent, error)
+
+	// MarketSubscribeDealUpdates replays the buffered lifecycle history of every deal known to the miner, then streams new
+	// lifecycle events for all deals as they occur. Live events that arrive while the subscriber's buffer is full are
+	// dropped. The returned channel is closed when ctx is done.
+	MarketSubscribeDealUpdates(ctx context.Context) (<-chan MarketDealEvent, error)
+}
+
+// MarketDealEvent is a single entry in a deal's lifecycle history, as
+// recorded by the miner's deal status log.
+type MarketDealEvent struct {
+	ProposalCid cid.Cid
+	Timestamp   time.Time
+	State       string
+	Message     string
+
+	// FundsCommitted is the client and provider collateral committed to the deal; storage payments are not included.
+	FundsCommitted types.BigInt
+	// PublishCid is the CID of the message that published the deal on chain, once available.
+	PublishCid *cid.Cid
+	// SectorNumber is the sector the deal's data was packed into, once sealed.
+	SectorNumber *abi.SectorNumber
+
+	// Sent and Received are the deal's current data-transfer byte counters, if a transfer is active.
+	Sent     uint64
+	Received uint64
+}
+
+// DataTransferChannel describes the state of a single go-data-transfer
+// channel backing a storage or retrieval deal, as surfaced to CLI operators
+// debugging a stuck transfer.
+type DataTransferChannel struct {
+	TransferID datatransfer.TransferID
+	Status     datatransfer.Status
+	BaseCID    cid.Cid
+	IsPull     bool
+	Voucher    string
+	Message    string
+	OtherPeer  peer.ID
+	Sent       uint64
+	Received   uint64
+}
@@ -0,0 +1,125 @@
+package apistruct
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-address"
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// StorageMinerStruct implements api.StorageMiner by proxying every call over
+// JSON-RPC: each Internal field is populated by the go-jsonrpc client with a
+// function that performs the actual network round-trip, keyed by the
+// exported method name below it.
+type StorageMinerStruct struct {
+	Internal struct {
+		ActorAddress    func(ctx context.Context) (address.Address, error)                      `perm:"read"`
+		ActorSectorSize func(ctx context.Context, addr address.Address) (abi.SectorSize, error) `perm:"read"`
+
+		MarketSetAsk func(ctx context.Context, price types.BigInt, blocksDuration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error `perm:"admin"`
+		MarketGetAsk func(ctx context.Context) (*storagemarket.SignedStorageAsk, error)                                                                                     `perm:"read"`
+
+		MarketSetRetrievalAsk func(ctx context.Context, rask *retrievalmarket.Ask) error `perm:"admin"`
+		MarketGetRetrievalAsk func(ctx context.Context) (*retrievalmarket.Ask, error)    `perm:"read"`
+
+		DealsSetAcceptingStorageDeals func(ctx context.Context, b bool) error                               `perm:"admin"`
+		DealsImportData               func(ctx context.Context, dealProposalCid cid.Cid, file string) error `perm:"admin"`
+
+		MarketListIncompleteDeals func(ctx context.Context) ([]storagemarket.MinerDeal, error) `perm:"read"`
+
+		MarketListDataTransfers   func(ctx context.Context) ([]api.DataTransferChannel, error)                       `perm:"write"`
+		MarketDataTransferUpdates func(ctx context.Context) (<-chan api.DataTransferChannel, error)                  `perm:"write"`
+		MarketRestartDataTransfer func(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error `perm:"write"`
+		MarketCancelDataTransfer  func(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error `perm:"write"`
+
+		MarketSetPieceCidBlocklist   func(ctx context.Context, blocklist []cid.Cid) error                `perm:"admin"`
+		MarketSetClientAllowlist     func(ctx context.Context, allowlist []peer.ID) error                `perm:"admin"`
+		MarketSetClientPriceOverride func(ctx context.Context, client peer.ID, price types.BigInt) error `perm:"admin"`
+
+		MarketGetDealUpdates       func(ctx context.Context, proposalCid cid.Cid) ([]api.MarketDealEvent, error) `perm:"read"`
+		MarketSubscribeDealUpdates func(ctx context.Context) (<-chan api.MarketDealEvent, error)                 `perm:"read"`
+	}
+}
+
+func (c *StorageMinerStruct) ActorAddress(ctx context.Context) (address.Address, error) {
+	return c.Internal.ActorAddress(ctx)
+}
+
+func (c *StorageMinerStruct) ActorSectorSize(ctx context.Context, addr address.Address) (abi.SectorSize, error) {
+	return c.Internal.ActorSectorSize(ctx, addr)
+}
+
+func (c *StorageMinerStruct) MarketSetAsk(ctx context.Context, price types.BigInt, blocksDuration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error {
+	return c.Internal.MarketSetAsk(ctx, price, blocksDuration, minPieceSize, maxPieceSize)
+}
+
+func (c *StorageMinerStruct) MarketGetAsk(ctx context.Context) (*storagemarket.SignedStorageAsk, error) {
+	return c.Internal.MarketGetAsk(ctx)
+}
+
+func (c *StorageMinerStruct) MarketSetRetrievalAsk(ctx context.Context, rask *retrievalmarket.Ask) error {
+	return c.Internal.MarketSetRetrievalAsk(ctx, rask)
+}
+
+func (c *StorageMinerStruct) MarketGetRetrievalAsk(ctx context.Context) (*retrievalmarket.Ask, error) {
+	return c.Internal.MarketGetRetrievalAsk(ctx)
+}
+
+func (c *StorageMinerStruct) DealsSetAcceptingStorageDeals(ctx context.Context, b bool) error {
+	return c.Internal.DealsSetAcceptingStorageDeals(ctx, b)
+}
+
+func (c *StorageMinerStruct) DealsImportData(ctx context.Context, dealProposalCid cid.Cid, file string) error {
+	return c.Internal.DealsImportData(ctx, dealProposalCid, file)
+}
+
+func (c *StorageMinerStruct) MarketListIncompleteDeals(ctx context.Context) ([]storagemarket.MinerDeal, error) {
+	return c.Internal.MarketListIncompleteDeals(ctx)
+}
+
+func (c *StorageMinerStruct) MarketListDataTransfers(ctx context.Context) ([]api.DataTransferChannel, error) {
+	return c.Internal.MarketListDataTransfers(ctx)
+}
+
+func (c *StorageMinerStruct) MarketDataTransferUpdates(ctx context.Context) (<-chan api.DataTransferChannel, error) {
+	return c.Internal.MarketDataTransferUpdates(ctx)
+}
+
+func (c *StorageMinerStruct) MarketRestartDataTransfer(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error {
+	return c.Internal.MarketRestartDataTransfer(ctx, transferID, other)
+}
+
+func (c *StorageMinerStruct) MarketCancelDataTransfer(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error {
+	return c.Internal.MarketCancelDataTransfer(ctx, transferID, other)
+}
+
+func (c *StorageMinerStruct) MarketSetPieceCidBlocklist(ctx context.Context, blocklist []cid.Cid) error {
+	return c.Internal.MarketSetPieceCidBlocklist(ctx, blocklist)
+}
+
+func (c *StorageMinerStruct) MarketSetClientAllowlist(ctx context.Context, allowlist []peer.ID) error {
+	return c.Internal.MarketSetClientAllowlist(ctx, allowlist)
+}
+
+func (c *StorageMinerStruct) MarketSetClientPriceOverride(ctx context.Context, client peer.ID, price types.BigInt) error {
+	return c.Internal.MarketSetClientPriceOverride(ctx, client, price)
+}
+
+func (c *StorageMinerStruct) MarketGetDealUpdates(ctx context.Context, proposalCid cid.Cid) ([]api.MarketDealEvent, error) {
+	return c.Internal.MarketGetDealUpdates(ctx, proposalCid)
+}
+
+func (c *StorageMinerStruct) MarketSubscribeDealUpdates(ctx context.Context) (<-chan api.MarketDealEvent, error) {
+	return c.Internal.MarketSubscribeDealUpdates(ctx)
+}
+
+var _ api.StorageMiner = &StorageMinerStruct{}
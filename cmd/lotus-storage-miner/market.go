@@ -1,20 +1,28 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/docker/go-units"
 	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 
+	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/types"
 	lcli "github.com/filecoin-project/lotus/cli"
@@ -187,6 +195,83 @@ var getAskCmd = &cli.Command{
 	},
 }
 
+var setRetrievalAskCmd = &cli.Command{
+	Name:  "set-retrieval-ask",
+	Usage: "Configure the miner's retrieval ask",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:     "price",
+			Usage:    "Set the price of the retrieval ask (specified as FIL / Byte) to `PRICE`",
+			Required: true,
+		},
+		&cli.Uint64Flag{
+			Name:  "unseal-price",
+			Usage: "Set the price to unseal a piece (specified as FIL) to `PRICE`",
+			Value: 0,
+		},
+		&cli.Uint64Flag{
+			Name:  "payment-interval",
+			Usage: "Set the payment interval (in bytes) for retrieval to `INTERVAL`",
+			Value: uint64(retrievalmarket.DefaultPaymentInterval),
+		},
+		&cli.Uint64Flag{
+			Name:  "payment-interval-increase",
+			Usage: "Set the payment interval increase (in bytes) for retrieval to `INTERVAL`",
+			Value: uint64(retrievalmarket.DefaultPaymentIntervalIncrease),
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		ask := retrievalmarket.Ask{
+			PricePerByte:            types.NewInt(cctx.Uint64("price")),
+			UnsealPrice:             types.NewInt(cctx.Uint64("unseal-price")),
+			PaymentInterval:         cctx.Uint64("payment-interval"),
+			PaymentIntervalIncrease: cctx.Uint64("payment-interval-increase"),
+		}
+
+		return api.MarketSetRetrievalAsk(ctx, &ask)
+	},
+}
+
+var getRetrievalAskCmd = &cli.Command{
+	Name:  "get-retrieval-ask",
+	Usage: "Get the miner's retrieval ask",
+	Flags: []cli.Flag{},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		ask, err := api.MarketGetRetrievalAsk(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Price per Byte\tUnseal Price\tPayment Interval\tPayment Interval Increase\n")
+		if ask == nil {
+			fmt.Fprintf(w, "<miner does not have a retrieval ask>\n")
+
+			return w.Flush()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", ask.PricePerByte, ask.UnsealPrice, ask.PaymentInterval, ask.PaymentIntervalIncrease)
+
+		return w.Flush()
+	},
+}
+
 var dealsCmd = &cli.Command{
 	Name:  "deals",
 	Usage: "interact with your deals",
@@ -197,6 +282,216 @@ var dealsCmd = &cli.Command{
 		disableCmd,
 		setAskCmd,
 		getAskCmd,
+		setRetrievalAskCmd,
+		getRetrievalAskCmd,
+		dataTransfersCmd,
+		dealsPolicyCmd,
+		dealsInspectCmd,
+		dealsEventsCmd,
+	},
+}
+
+var dealsInspectCmd = &cli.Command{
+	Name:      "inspect",
+	Usage:     "Print the full lifecycle history of a single deal",
+	ArgsUsage: "<proposal CID>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.New("must specify proposal CID")
+		}
+
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		propCid, err := cid.Decode(cctx.Args().Get(0))
+		if err != nil {
+			return err
+		}
+
+		history, err := api.MarketGetDealUpdates(ctx, propCid)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Time\tState\tMessage\tFunds Committed\tPublish CID\tSector\tTransfer\n")
+
+		for _, u := range history {
+			publishCid := "<none>"
+			if u.PublishCid != nil {
+				publishCid = u.PublishCid.String()
+			}
+
+			sector := "<none>"
+			if u.SectorNumber != nil {
+				sector = fmt.Sprintf("%d", *u.SectorNumber)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\n",
+				u.Timestamp.Format(time.RFC3339), u.State, u.Message, u.FundsCommitted, publishCid, sector, u.Sent, u.Received)
+		}
+
+		return w.Flush()
+	},
+}
+
+var dealsEventsCmd = &cli.Command{
+	Name:  "events",
+	Usage: "Stream deal state transitions as they occur",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "follow",
+			Usage: "keep streaming updates until interrupted",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		updates, err := api.MarketSubscribeDealUpdates(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Time\tDeal\tState\tMessage\n")
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if !cctx.Bool("follow") {
+			// Without --follow, print only the already-buffered backlog
+			// MarketSubscribeDealUpdates replays on connect, then stop
+			// before the channel would block waiting for a live event.
+			for {
+				select {
+				case u, ok := <-updates:
+					if !ok {
+						return nil
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Timestamp.Format(time.RFC3339), u.ProposalCid, u.State, u.Message)
+					if err := w.Flush(); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		}
+
+		for u := range updates {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Timestamp.Format(time.RFC3339), u.ProposalCid, u.State, u.Message)
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+var dealsPolicyCmd = &cli.Command{
+	Name:  "policy",
+	Usage: "Manage the miner's deal acceptance policy",
+	Subcommands: []*cli.Command{
+		policySetPieceCidBlocklistCmd,
+		policySetClientAllowlistCmd,
+		policySetPriceScheduleCmd,
+	},
+}
+
+var policySetPieceCidBlocklistCmd = &cli.Command{
+	Name:      "set-piece-cid-blocklist",
+	Usage:     "Reject deal proposals whose piece CID appears in this list",
+	ArgsUsage: "<piece CID> ...",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		var blocklist []cid.Cid
+		for _, s := range cctx.Args().Slice() {
+			c, err := cid.Decode(s)
+			if err != nil {
+				return xerrors.Errorf("parsing piece CID %q: %w", s, err)
+			}
+
+			blocklist = append(blocklist, c)
+		}
+
+		return api.MarketSetPieceCidBlocklist(ctx, blocklist)
+	},
+}
+
+var policySetClientAllowlistCmd = &cli.Command{
+	Name:      "set-client-allowlist",
+	Usage:     "Only accept deal proposals from clients in this list (empty list allows any client)",
+	ArgsUsage: "<client peer ID> ...",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		var allowlist []peer.ID
+		for _, s := range cctx.Args().Slice() {
+			p, err := peer.Decode(s)
+			if err != nil {
+				return xerrors.Errorf("parsing client peer ID %q: %w", s, err)
+			}
+
+			allowlist = append(allowlist, p)
+		}
+
+		return api.MarketSetClientAllowlist(ctx, allowlist)
+	},
+}
+
+var policySetPriceScheduleCmd = &cli.Command{
+	Name:      "set-price-schedule",
+	Usage:     "Override the base ask price for a specific client",
+	ArgsUsage: "<client peer ID> <price>",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		if cctx.Args().Len() != 2 {
+			return xerrors.New("must specify client peer ID and price")
+		}
+
+		p, err := peer.Decode(cctx.Args().Get(0))
+		if err != nil {
+			return xerrors.Errorf("parsing client peer ID: %w", err)
+		}
+
+		price, err := types.BigFromString(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing price: %w", err)
+		}
+
+		return api.MarketSetClientPriceOverride(ctx, p, price)
 	},
 }
 
@@ -232,6 +527,30 @@ var dealsImportDataCmd = &cli.Command{
 var dealsListCmd = &cli.Command{
 	Name:  "list",
 	Usage: "List all deals for this miner",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "print deals in the given format: text, json",
+			Value: "text",
+		},
+		&cli.BoolFlag{
+			Name:    "verbose",
+			Usage:   "print more detail about each deal, including data-transfer progress",
+			Aliases: []string{"v"},
+		},
+		&cli.StringFlag{
+			Name:  "state",
+			Usage: "only show deals in the given state(s), comma-separated",
+		},
+		&cli.StringFlag{
+			Name:  "client",
+			Usage: "only show deals from the given client peer ID",
+		},
+		&cli.BoolFlag{
+			Name:  "watch",
+			Usage: "watch deal updates in real-time, rather than a one time list",
+		},
+	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := lcli.GetStorageMinerAPI(cctx)
 		if err != nil {
@@ -241,17 +560,268 @@ var dealsListCmd = &cli.Command{
 
 		ctx := lcli.DaemonContext(cctx)
 
+		switch cctx.String("format") {
+		case "text":
+			// fall through to the table output below
+		case "json":
+			deals, err := api.MarketListIncompleteDeals(ctx)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(deals, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(data))
+			return nil
+		default:
+			return xerrors.Errorf("unrecognized format %q", cctx.String("format"))
+		}
+
+		var clientAddr peer.ID
+		if cctx.IsSet("client") {
+			clientAddr, err = peer.Decode(cctx.String("client"))
+			if err != nil {
+				return xerrors.Errorf("parsing client peer ID: %w", err)
+			}
+		}
+
+		if cctx.Bool("watch") {
+			for {
+				deals, err := api.MarketListIncompleteDeals(ctx)
+				if err != nil {
+					return err
+				}
+
+				if err := outputStorageDeals(os.Stdout, api, ctx, deals, cctx.String("state"), clientAddr, cctx.Bool("verbose")); err != nil {
+					return err
+				}
+
+				time.Sleep(time.Second)
+
+				fmt.Print("\033[2J") // clear screen
+				fmt.Print("\033[H")  // move cursor to top-left
+			}
+		}
+
 		deals, err := api.MarketListIncompleteDeals(ctx)
 		if err != nil {
 			return err
 		}
 
-		data, err := json.MarshalIndent(deals, "", "  ")
+		return outputStorageDeals(os.Stdout, api, ctx, deals, cctx.String("state"), clientAddr, cctx.Bool("verbose"))
+	},
+}
+
+func outputStorageDeals(out *os.File, minerApi api.StorageMiner, ctx context.Context, deals []storagemarket.MinerDeal, stateFilter string, clientFilter peer.ID, verbose bool) error {
+	sort.Slice(deals, func(i, j int) bool {
+		return deals[i].CreationTime.Time().Before(deals[j].CreationTime.Time())
+	})
+
+	var states map[string]struct{}
+	if stateFilter != "" {
+		states = make(map[string]struct{})
+		for _, s := range strings.Split(stateFilter, ",") {
+			states[strings.TrimSpace(s)] = struct{}{}
+		}
+	}
+
+	var transferChannels []api.DataTransferChannel
+	if verbose {
+		var err error
+		transferChannels, err = minerApi.MarketListDataTransfers(ctx)
 		if err != nil {
 			return err
 		}
+	}
+
+	w := tabwriter.NewWriter(out, 2, 4, 2, ' ', 0)
+	if verbose {
+		fmt.Fprintf(w, "ProposalCID\tDealID\tState\tClient\tPricePerEpoch\tPieceSize\tStart\tEnd\tTransfer\tMessage\n")
+	} else {
+		fmt.Fprintf(w, "ProposalCID\tDealID\tState\tClient\tPricePerEpoch\tPieceSize\tStart\tEnd\tMessage\n")
+	}
+
+	for _, deal := range deals {
+		stateName := storagemarket.DealStates[deal.State]
+
+		if states != nil {
+			if _, ok := states[stateName]; !ok {
+				continue
+			}
+		}
+
+		if clientFilter != "" && deal.Client != clientFilter {
+			continue
+		}
+
+		propcid := deal.ProposalCid.String()
+		propcid = "..." + propcid[len(propcid)-8:]
+
+		if verbose {
+			// Sent/Received order matches printDataTransfersTable so an
+			// operator reading both tables sees the same field order.
+			transfer := "N/A"
+			for _, ch := range transferChannels {
+				if ch.BaseCID == deal.Ref.Root {
+					transfer = fmt.Sprintf("#%d %d/%d", ch.TransferID, ch.Sent, ch.Received)
+				}
+			}
+
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+				propcid, deal.DealID, stateName, deal.Client, deal.Proposal.StoragePricePerEpoch, types.SizeStr(types.NewInt(uint64(deal.Proposal.PieceSize))),
+				deal.Proposal.StartEpoch, deal.Proposal.EndEpoch, transfer, deal.Message)
+		} else {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+				propcid, deal.DealID, stateName, deal.Client, deal.Proposal.StoragePricePerEpoch, types.SizeStr(types.NewInt(uint64(deal.Proposal.PieceSize))),
+				deal.Proposal.StartEpoch, deal.Proposal.EndEpoch, deal.Message)
+		}
+	}
+
+	return w.Flush()
+}
 
-		fmt.Println(string(data))
+var dataTransfersCmd = &cli.Command{
+	Name:  "data-transfers",
+	Usage: "Manage data transfers for storage and retrieval deals",
+	Subcommands: []*cli.Command{
+		transfersListCmd,
+		marketRestartTransfer,
+		marketCancelTransfer,
+	},
+}
+
+var transfersListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List ongoing data transfers for this miner",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "watch",
+			Usage: "watch deal updates in real-time, rather than a one time list",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		if cctx.Bool("watch") {
+			updates, err := api.MarketDataTransferUpdates(ctx)
+			if err != nil {
+				return err
+			}
+
+			for {
+				channels, err := api.MarketListDataTransfers(ctx)
+				if err != nil {
+					return err
+				}
+
+				printDataTransfersTable(os.Stdout, channels)
+				select {
+				case <-time.After(time.Second):
+				case <-updates:
+				}
+
+				fmt.Print("\033[2J") // clear screen
+				fmt.Print("\033[H")  // move cursor to top-left
+			}
+		}
+
+		channels, err := api.MarketListDataTransfers(ctx)
+		if err != nil {
+			return err
+		}
+
+		printDataTransfersTable(os.Stdout, channels)
 		return nil
 	},
 }
+
+// printDataTransfersTable always prints transfer ID, base CID, channel
+// status, sent/received bytes, remote peer, voucher type, and direction —
+// operators debugging a stuck transfer need every one of these by default.
+func printDataTransfersTable(out *os.File, channels []api.DataTransferChannel) {
+	w := tabwriter.NewWriter(out, 2, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Transfer ID\tBase CID\tStatus\tSent\tReceived\tPeer\tVoucher\tDirection\n")
+
+	for _, channel := range channels {
+		direction := "push"
+		if channel.IsPull {
+			direction = "pull"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+			channel.TransferID, channel.BaseCID, channel.Status, channel.Sent, channel.Received, channel.OtherPeer, channel.Voucher, direction)
+	}
+
+	_ = w.Flush()
+}
+
+var marketRestartTransfer = &cli.Command{
+	Name:      "restart",
+	Usage:     "Force restart a stalled data transfer",
+	ArgsUsage: "<transfer ID> <peer ID>",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		if cctx.Args().Len() != 2 {
+			return xerrors.New("must specify transfer ID and peer ID")
+		}
+
+		transferID, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing transfer ID: %w", err)
+		}
+
+		p, err := peer.Decode(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing peer ID: %w", err)
+		}
+
+		return api.MarketRestartDataTransfer(ctx, datatransfer.TransferID(transferID), p)
+	},
+}
+
+var marketCancelTransfer = &cli.Command{
+	Name:      "cancel",
+	Usage:     "Force cancel a data transfer",
+	ArgsUsage: "<transfer ID> <peer ID>",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.DaemonContext(cctx)
+
+		if cctx.Args().Len() != 2 {
+			return xerrors.New("must specify transfer ID and peer ID")
+		}
+
+		transferID, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing transfer ID: %w", err)
+		}
+
+		p, err := peer.Decode(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing peer ID: %w", err)
+		}
+
+		return api.MarketCancelDataTransfer(ctx, datatransfer.TransferID(transferID), p)
+	},
+}
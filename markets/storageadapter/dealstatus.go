@@ -0,0 +1,121 @@
+package storageadapter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// dealHistorySize bounds the per-deal ring buffer so a deal that churns
+// through many state transitions can't grow the log without bound.
+const dealHistorySize = 64
+
+// DealStatusLog buffers the lifecycle history of every deal the miner knows
+// about, in a fixed-size ring buffer per deal, and fans events out to any
+// live subscribers. It backs both api.StorageMiner.MarketGetDealUpdates
+// (a synchronous read of one deal's buffered history) and
+// api.StorageMiner.MarketSubscribeDealUpdates (a replay-then-live stream
+// across all deals), so CLI operators can diagnose a stuck deal without
+// tailing the miner's log.
+type DealStatusLog struct {
+	lk   sync.Mutex
+	logs map[cid.Cid][]api.MarketDealEvent
+	subs map[uint64]chan api.MarketDealEvent
+	next uint64
+}
+
+func NewDealStatusLog() *DealStatusLog {
+	return &DealStatusLog{
+		logs: map[cid.Cid][]api.MarketDealEvent{},
+		subs: map[uint64]chan api.MarketDealEvent{},
+	}
+}
+
+// Record appends an event to the given deal's history and fans it out to
+// every live subscriber.
+func (l *DealStatusLog) Record(evt api.MarketDealEvent) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	log := append(l.logs[evt.ProposalCid], evt)
+	if len(log) > dealHistorySize {
+		log = log[len(log)-dealHistorySize:]
+	}
+	l.logs[evt.ProposalCid] = log
+
+	for _, sub := range l.subs {
+		select {
+		case sub <- evt:
+		default:
+			// a slow CLI consumer must never block deal processing
+		}
+	}
+}
+
+// History returns the buffered lifecycle events for a single deal, oldest first.
+func (l *DealStatusLog) History(proposalCid cid.Cid) []api.MarketDealEvent {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	log := l.logs[proposalCid]
+	out := make([]api.MarketDealEvent, len(log))
+	copy(out, log)
+	return out
+}
+
+// Subscribe returns a channel that first replays every currently-buffered
+// event for every deal (oldest first), then streams new events as they're
+// recorded; a live event recorded while this subscriber's buffer is full is
+// dropped (see Record). The backlog is queued onto the returned channel before Subscribe
+// returns, so a caller that starts draining the channel immediately is
+// guaranteed to see the full backlog rather than racing the replay. The
+// channel is closed once ctx is done.
+func (l *DealStatusLog) Subscribe(ctx context.Context) <-chan api.MarketDealEvent {
+	l.lk.Lock()
+
+	var backlog []api.MarketDealEvent
+	for _, log := range l.logs {
+		backlog = append(backlog, log...)
+	}
+
+	id := l.next
+	l.next++
+	sub := make(chan api.MarketDealEvent, 256)
+	l.subs[id] = sub
+
+	l.lk.Unlock()
+
+	out := make(chan api.MarketDealEvent, len(backlog)+256)
+	for _, evt := range backlog {
+		out <- evt
+	}
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case evt := <-sub:
+				select {
+				case out <- evt:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		l.lk.Lock()
+		delete(l.subs, id)
+		l.lk.Unlock()
+	}()
+
+	return out
+}
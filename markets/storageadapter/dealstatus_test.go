@@ -0,0 +1,94 @@
+package storageadapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+var dealStatusCidCounter int
+
+func newDealStatusTestCid(t *testing.T) cid.Cid {
+	t.Helper()
+
+	dealStatusCidCounter++
+	mh, err := multihash.Sum([]byte(fmt.Sprintf("dealstatus-test-%d", dealStatusCidCounter)), multihash.SHA2_256, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(cid.Raw, mh)
+}
+
+func TestDealStatusLogHistory(t *testing.T) {
+	l := NewDealStatusLog()
+
+	proposalCid := newDealStatusTestCid(t)
+	l.Record(api.MarketDealEvent{ProposalCid: proposalCid, State: "StorageDealProposalAccepted"})
+	l.Record(api.MarketDealEvent{ProposalCid: proposalCid, State: "StorageDealSealing"})
+	l.Record(api.MarketDealEvent{ProposalCid: newDealStatusTestCid(t), State: "StorageDealProposalAccepted"})
+
+	history := l.History(proposalCid)
+	require.Len(t, history, 2)
+	require.Equal(t, "StorageDealProposalAccepted", history[0].State)
+	require.Equal(t, "StorageDealSealing", history[1].State)
+}
+
+func TestDealStatusLogHistoryEviction(t *testing.T) {
+	l := NewDealStatusLog()
+
+	proposalCid := newDealStatusTestCid(t)
+	for i := 0; i < dealHistorySize+10; i++ {
+		l.Record(api.MarketDealEvent{ProposalCid: proposalCid, State: fmt.Sprintf("state-%d", i)})
+	}
+
+	history := l.History(proposalCid)
+	require.Len(t, history, dealHistorySize)
+	require.Equal(t, "state-10", history[0].State)
+	require.Equal(t, fmt.Sprintf("state-%d", dealHistorySize+9), history[len(history)-1].State)
+}
+
+func TestDealStatusLogSubscribeReplaysBacklogSynchronously(t *testing.T) {
+	l := NewDealStatusLog()
+
+	proposalCid := newDealStatusTestCid(t)
+	l.Record(api.MarketDealEvent{ProposalCid: proposalCid, State: "StorageDealProposalAccepted"})
+	l.Record(api.MarketDealEvent{ProposalCid: proposalCid, State: "StorageDealSealing"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := l.Subscribe(ctx)
+
+	var backlog []api.MarketDealEvent
+drain:
+	for {
+		select {
+		case evt := <-updates:
+			backlog = append(backlog, evt)
+		default:
+			break drain
+		}
+	}
+
+	require.Len(t, backlog, 2)
+}
+
+func TestDealStatusLogSubscribeStreamsLiveEvents(t *testing.T) {
+	l := NewDealStatusLog()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := l.Subscribe(ctx)
+
+	proposalCid := newDealStatusTestCid(t)
+	l.Record(api.MarketDealEvent{ProposalCid: proposalCid, State: "StorageDealProposalAccepted"})
+
+	evt := <-updates
+	require.Equal(t, proposalCid, evt.ProposalCid)
+	require.Equal(t, "StorageDealProposalAccepted", evt.State)
+}
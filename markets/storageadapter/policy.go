@@ -0,0 +1,161 @@
+package storageadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var policyKey = datastore.NewKey("/policy")
+
+// DealFilter is the miner's deal acceptance policy: a piece-CID blocklist,
+// an optional client allowlist, and per-client ask-price overrides, all
+// persisted in the miner's datastore so they survive a restart. Its Filter
+// method is wired into the storage market provider (via
+// storageimpl.CustomDealDeciderOpt(df.Filter) at provider-construction time)
+// as the deal-acceptance hook consulted before the provider will sign an ask
+// response to an incoming proposal.
+type DealFilter struct {
+	ds datastore.Datastore
+
+	lk        sync.Mutex
+	Blocklist []cid.Cid                `json:"Blocklist"`
+	Allowlist []peer.ID                `json:"Allowlist"`
+	Overrides map[peer.ID]types.BigInt `json:"Overrides"`
+
+	// askPrice returns the miner's current base storage ask price; it's
+	// injected rather than looked up directly so the filter doesn't need to
+	// depend on the full storage market provider.
+	askPrice func() types.BigInt
+
+	// accepting reports whether the miner currently wants new storage deal
+	// proposals at all; injected for the same reason as askPrice.
+	accepting func() bool
+}
+
+// NewDealFilter loads any previously persisted policy from ds and returns a
+// DealFilter ready to consult or update. askPrice supplies the miner's base
+// ask price when no per-client override applies, and accepting reports
+// whether the miner currently wants new storage deals at all.
+func NewDealFilter(ds datastore.Datastore, askPrice func() types.BigInt, accepting func() bool) (*DealFilter, error) {
+	f := &DealFilter{
+		ds:        namespace.Wrap(ds, datastore.NewKey("/deal-policy")),
+		Overrides: map[peer.ID]types.BigInt{},
+		askPrice:  askPrice,
+		accepting: accepting,
+	}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *DealFilter) load() error {
+	data, err := f.ds.Get(policyKey)
+	if err == datastore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, f)
+}
+
+func (f *DealFilter) persist() error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return f.ds.Put(policyKey, data)
+}
+
+// SetPieceCidBlocklist replaces the set of piece CIDs the miner refuses to accept deals for.
+func (f *DealFilter) SetPieceCidBlocklist(ctx context.Context, blocklist []cid.Cid) error {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	f.Blocklist = blocklist
+	return f.persist()
+}
+
+// SetClientAllowlist replaces the set of clients the miner accepts deals from. An empty list allows any client.
+func (f *DealFilter) SetClientAllowlist(ctx context.Context, allowlist []peer.ID) error {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	f.Allowlist = allowlist
+	return f.persist()
+}
+
+// SetClientPriceOverride sets a per-client override for the base ask price. A zero price clears the override.
+func (f *DealFilter) SetClientPriceOverride(ctx context.Context, client peer.ID, price types.BigInt) error {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	if price.IsZero() {
+		delete(f.Overrides, client)
+	} else {
+		f.Overrides[client] = price
+	}
+
+	return f.persist()
+}
+
+// Filter implements storagemarket.CustomDealDeciderFunc. It rejects every
+// deal while the miner isn't accepting storage deals, then rejects deals
+// whose piece CID is blocklisted, rejects deals from clients not in the
+// allowlist (when one is set), and enforces the client's price override (or
+// the base ask, if no override applies) against the proposed price.
+func (f *DealFilter) Filter(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	if !f.accepting() {
+		return false, "miner is not currently accepting storage deals", nil
+	}
+
+	for _, c := range f.Blocklist {
+		if c.Equals(deal.Proposal.PieceCID) {
+			return false, fmt.Sprintf("piece CID %s is blocklisted", deal.Proposal.PieceCID), nil
+		}
+	}
+
+	if len(f.Allowlist) > 0 {
+		allowed := false
+		for _, p := range f.Allowlist {
+			if p == deal.Client {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return false, fmt.Sprintf("client %s is not in the allowlist", deal.Client), nil
+		}
+	}
+
+	minPrice := f.askPrice()
+	if override, ok := f.Overrides[deal.Client]; ok {
+		minPrice = override
+	}
+
+	if deal.Proposal.StoragePricePerEpoch.LessThan(minPrice) {
+		return false, fmt.Sprintf("proposed price %s is below the required price %s for client %s", deal.Proposal.StoragePricePerEpoch, minPrice, deal.Client), nil
+	}
+
+	return true, "", nil
+}
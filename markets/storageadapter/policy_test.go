@@ -0,0 +1,116 @@
+package storageadapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func newTestDealFilter(t *testing.T, ask types.BigInt, accepting bool) *DealFilter {
+	t.Helper()
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	f, err := NewDealFilter(ds, func() types.BigInt { return ask }, func() bool { return accepting })
+	require.NoError(t, err)
+	return f
+}
+
+var testCidCounter int
+
+func newTestCid(t *testing.T) cid.Cid {
+	t.Helper()
+
+	testCidCounter++
+	mh, err := multihash.Sum([]byte(fmt.Sprintf("policy-test-%d", testCidCounter)), multihash.SHA2_256, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(cid.Raw, mh)
+}
+
+func newTestPeerID(t *testing.T) peer.ID {
+	t.Helper()
+
+	return peer.ID(newTestCid(t).Hash())
+}
+
+func testDeal(client peer.ID, pieceCID cid.Cid, price types.BigInt) storagemarket.MinerDeal {
+	deal := storagemarket.MinerDeal{}
+	deal.Client = client
+	deal.Proposal.PieceCID = pieceCID
+	deal.Proposal.StoragePricePerEpoch = price
+	return deal
+}
+
+func TestDealFilterRejectsWhenNotAccepting(t *testing.T) {
+	f := newTestDealFilter(t, types.NewInt(100), false)
+
+	ok, reason, err := f.Filter(context.Background(), testDeal(newTestPeerID(t), newTestCid(t), types.NewInt(100)))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "not currently accepting")
+}
+
+func TestDealFilterBlocklist(t *testing.T) {
+	f := newTestDealFilter(t, types.NewInt(100), true)
+
+	blocked := newTestCid(t)
+	require.NoError(t, f.SetPieceCidBlocklist(context.Background(), []cid.Cid{blocked}))
+
+	ok, reason, err := f.Filter(context.Background(), testDeal(newTestPeerID(t), blocked, types.NewInt(100)))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "blocklisted")
+
+	ok, _, err = f.Filter(context.Background(), testDeal(newTestPeerID(t), newTestCid(t), types.NewInt(100)))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestDealFilterAllowlist(t *testing.T) {
+	f := newTestDealFilter(t, types.NewInt(100), true)
+
+	allowed := newTestPeerID(t)
+	require.NoError(t, f.SetClientAllowlist(context.Background(), []peer.ID{allowed}))
+
+	ok, reason, err := f.Filter(context.Background(), testDeal(newTestPeerID(t), newTestCid(t), types.NewInt(100)))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "not in the allowlist")
+
+	ok, _, err = f.Filter(context.Background(), testDeal(allowed, newTestCid(t), types.NewInt(100)))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestDealFilterPriceOverride(t *testing.T) {
+	f := newTestDealFilter(t, types.NewInt(100), true)
+
+	client := newTestPeerID(t)
+
+	ok, reason, err := f.Filter(context.Background(), testDeal(client, newTestCid(t), types.NewInt(50)))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "below the required price")
+
+	require.NoError(t, f.SetClientPriceOverride(context.Background(), client, types.NewInt(10)))
+
+	ok, _, err = f.Filter(context.Background(), testDeal(client, newTestCid(t), types.NewInt(50)))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, f.SetClientPriceOverride(context.Background(), client, types.NewInt(0)))
+
+	ok, _, err = f.Filter(context.Background(), testDeal(client, newTestCid(t), types.NewInt(50)))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
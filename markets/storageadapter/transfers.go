@@ -0,0 +1,154 @@
+package storageadapter
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// TransferManager adapts a go-data-transfer Manager to the subset of
+// operations the storage miner node needs to expose over its API: listing
+// in-flight channels, watching them for state changes, and forcing a
+// restart or cancel when one gets stuck. It backs both the storage and the
+// retrieval deal flows, since they share the same underlying manager.
+type TransferManager struct {
+	dtm  datatransfer.Manager
+	self peer.ID
+}
+
+// NewTransferManager wraps dtm for use by the miner's API. self is the
+// miner's own libp2p peer ID, needed to tell which side of a channel's
+// initiator/responder pair is "us" versus the remote peer a CLI operator
+// names on the command line.
+func NewTransferManager(dtm datatransfer.Manager, self peer.ID) *TransferManager {
+	return &TransferManager{dtm: dtm, self: self}
+}
+
+// List returns the current state of every data-transfer channel known to the manager.
+func (m *TransferManager) List(ctx context.Context) ([]api.DataTransferChannel, error) {
+	inProgress, err := m.dtm.InProgressChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]api.DataTransferChannel, 0, len(inProgress))
+	for _, st := range inProgress {
+		out = append(out, toDataTransferChannel(st))
+	}
+
+	return out, nil
+}
+
+// Subscribe returns a channel that receives an update when any data-transfer
+// channel changes state. Updates that arrive while the channel's buffer is
+// full are dropped. The channel is closed once ctx is done.
+func (m *TransferManager) Subscribe(ctx context.Context) <-chan api.DataTransferChannel {
+	out := make(chan api.DataTransferChannel, 32)
+
+	unsub := m.dtm.SubscribeToEvents(func(_ datatransfer.Event, st datatransfer.ChannelState) {
+		select {
+		case out <- toDataTransferChannel(st):
+		default:
+			// a slow CLI consumer must never block transfer processing
+		}
+	})
+
+	go func() {
+		<-ctx.Done()
+		unsub()
+		close(out)
+	}()
+
+	return out
+}
+
+// Restart asks the manager to re-open a stalled channel with the given peer.
+func (m *TransferManager) Restart(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error {
+	id, err := m.channelID(ctx, transferID, other)
+	if err != nil {
+		return err
+	}
+
+	return m.dtm.RestartDataTransferChannel(ctx, id)
+}
+
+// Cancel force-closes an in-progress channel with the given peer.
+func (m *TransferManager) Cancel(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error {
+	id, err := m.channelID(ctx, transferID, other)
+	if err != nil {
+		return err
+	}
+
+	return m.dtm.CloseDataTransferChannel(ctx, id)
+}
+
+// Lookup returns the current state of the channel carrying data for
+// baseCID, if any channel is currently tracking it.
+func (m *TransferManager) Lookup(ctx context.Context, baseCID cid.Cid) (api.DataTransferChannel, bool) {
+	inProgress, err := m.dtm.InProgressChannels(ctx)
+	if err != nil {
+		return api.DataTransferChannel{}, false
+	}
+
+	for _, st := range inProgress {
+		if st.BaseCID() == baseCID {
+			return toDataTransferChannel(st), true
+		}
+	}
+
+	return api.DataTransferChannel{}, false
+}
+
+// channelID resolves (transferID, other) to the full ChannelID the manager
+// tracks it under. A ChannelID's Initiator/Responder pair identifies the two
+// actual participants of the channel — exactly one of which is always this
+// miner — so a transfer ID and a remote peer alone aren't enough to address
+// a channel; the manager's own in-progress set has to be searched to learn
+// which side of the pair is us.
+func (m *TransferManager) channelID(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) (datatransfer.ChannelID, error) {
+	inProgress, err := m.dtm.InProgressChannels(ctx)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
+
+	for id, st := range inProgress {
+		if st.TransferID() != transferID {
+			continue
+		}
+
+		if id.Initiator == other && id.Responder == m.self {
+			return id, nil
+		}
+
+		if id.Initiator == m.self && id.Responder == other {
+			return id, nil
+		}
+	}
+
+	return datatransfer.ChannelID{}, xerrors.Errorf("no data-transfer channel %d with peer %s", transferID, other)
+}
+
+func toDataTransferChannel(st datatransfer.ChannelState) api.DataTransferChannel {
+	voucher := ""
+	if v := st.Voucher(); v != nil {
+		voucher = string(v.Type())
+	}
+
+	return api.DataTransferChannel{
+		TransferID: st.TransferID(),
+		Status:     st.Status(),
+		BaseCID:    st.BaseCID(),
+		IsPull:     !st.IsSender(),
+		Voucher:    voucher,
+		Message:    st.Message(),
+		OtherPeer:  st.OtherPeer(),
+		Sent:       st.Sent(),
+		Received:   st.Received(),
+	}
+}
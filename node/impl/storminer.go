@@ -0,0 +1,240 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	storageimpl "github.com/filecoin-project/go-fil-markets/storagemarket/impl"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/markets/storageadapter"
+)
+
+// StorageMinerAPI is the concrete, in-process implementation of
+// api.StorageMiner that backs the miner's JSON-RPC endpoint.
+type StorageMinerAPI struct {
+	Miner    address.Address
+	SectorSz abi.SectorSize
+
+	StorageProvider   storagemarket.StorageProvider
+	RetrievalProvider retrievalmarket.RetrievalProvider
+
+	Transfers  *storageadapter.TransferManager
+	DealFilter *storageadapter.DealFilter
+	DealLog    *storageadapter.DealStatusLog
+
+	// acceptingStorageDeals is consulted by the storage market provider's
+	// deal-acceptance path (see markets/storageadapter.DealFilter) before it
+	// will sign an ask response for a new proposal.
+	acceptingStorageDeals int32
+}
+
+var _ api.StorageMiner = (*StorageMinerAPI)(nil)
+
+// NewStorageMinerAPI assembles a StorageMinerAPI ready to serve the miner's
+// JSON-RPC endpoint, wiring up the data-transfer manager, deal-acceptance
+// policy, and deal-status log added in this series so that none of their
+// backing fields are left nil. newStorageProvider is called with the
+// options the node needs to apply at construction time (e.g. the deal
+// acceptance policy's decider hook); it's a constructor func rather than an
+// already-built provider so that those options can be threaded through.
+func NewStorageMinerAPI(
+	miner address.Address,
+	sectorSize abi.SectorSize,
+	self peer.ID,
+	ds datastore.Batching,
+	dtm datatransfer.Manager,
+	newStorageProvider func(opts ...storagemarket.StorageProviderOption) (storagemarket.StorageProvider, error),
+	rp retrievalmarket.RetrievalProvider,
+) (*StorageMinerAPI, error) {
+	sm := &StorageMinerAPI{
+		Miner:             miner,
+		SectorSz:          sectorSize,
+		RetrievalProvider: rp,
+		Transfers:         storageadapter.NewTransferManager(dtm, self),
+		DealLog:           storageadapter.NewDealStatusLog(),
+	}
+
+	df, err := storageadapter.NewDealFilter(ds, sm.currentAskPrice, sm.AcceptingStorageDeals)
+	if err != nil {
+		return nil, xerrors.Errorf("loading deal acceptance policy: %w", err)
+	}
+	sm.DealFilter = df
+
+	sp, err := newStorageProvider(storageimpl.CustomDealDeciderOpt(df.Filter))
+	if err != nil {
+		return nil, xerrors.Errorf("constructing storage provider: %w", err)
+	}
+	sm.StorageProvider = sp
+
+	sm.StorageProvider.SubscribeToEvents(func(_ storagemarket.ProviderEvent, deal storagemarket.MinerDeal) {
+		sm.recordStorageDealEvent(deal)
+	})
+	sm.RetrievalProvider.SubscribeToEvents(func(_ retrievalmarket.ProviderEvent, deal retrievalmarket.ProviderDealState) {
+		sm.recordRetrievalDealEvent(deal)
+	})
+
+	return sm, nil
+}
+
+// recordStorageDealEvent translates a storage market state transition into a
+// MarketDealEvent and appends it to the miner's deal status log.
+func (sm *StorageMinerAPI) recordStorageDealEvent(deal storagemarket.MinerDeal) {
+	evt := api.MarketDealEvent{
+		ProposalCid:    deal.ProposalCid,
+		Timestamp:      time.Now(),
+		State:          storagemarket.DealStates[deal.State],
+		Message:        deal.Message,
+		FundsCommitted: types.BigAdd(deal.Proposal.ClientCollateral, deal.Proposal.ProviderCollateral),
+		PublishCid:     deal.PublishCid,
+	}
+
+	if deal.SectorNumber != 0 {
+		sectorNumber := deal.SectorNumber
+		evt.SectorNumber = &sectorNumber
+	}
+
+	if dtc, ok := sm.Transfers.Lookup(context.TODO(), deal.Ref.Root); ok {
+		evt.Sent = dtc.Sent
+		evt.Received = dtc.Received
+	}
+
+	sm.DealLog.Record(evt)
+}
+
+// recordRetrievalDealEvent translates a retrieval market state transition
+// into a MarketDealEvent and appends it to the miner's deal status log.
+// Retrieval deals aren't identified by a proposal CID the way storage deals
+// are, so the payload CID is used in its place; it's the closest analog and
+// lets "deals inspect" find a retrieval's history for the content a client
+// fetched.
+func (sm *StorageMinerAPI) recordRetrievalDealEvent(deal retrievalmarket.ProviderDealState) {
+	evt := api.MarketDealEvent{
+		ProposalCid: deal.PayloadCID,
+		Timestamp:   time.Now(),
+		State:       retrievalmarket.DealStatuses[deal.Status],
+		Message:     deal.Message,
+		Sent:        deal.TotalSent,
+	}
+
+	sm.DealLog.Record(evt)
+}
+
+func (sm *StorageMinerAPI) currentAskPrice() types.BigInt {
+	ask := sm.StorageProvider.GetAsk()
+	if ask == nil || ask.Ask == nil {
+		return types.NewInt(0)
+	}
+
+	return ask.Ask.Price
+}
+
+func (sm *StorageMinerAPI) ActorAddress(ctx context.Context) (address.Address, error) {
+	return sm.Miner, nil
+}
+
+func (sm *StorageMinerAPI) ActorSectorSize(ctx context.Context, addr address.Address) (abi.SectorSize, error) {
+	return sm.SectorSz, nil
+}
+
+func (sm *StorageMinerAPI) MarketSetAsk(ctx context.Context, price types.BigInt, blocksDuration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error {
+	options := []storagemarket.StorageAskOption{
+		storagemarket.MinPieceSize(minPieceSize),
+		storagemarket.MaxPieceSize(maxPieceSize),
+	}
+
+	return sm.StorageProvider.SetAsk(price, blocksDuration, options...)
+}
+
+func (sm *StorageMinerAPI) MarketGetAsk(ctx context.Context) (*storagemarket.SignedStorageAsk, error) {
+	return sm.StorageProvider.GetAsk(), nil
+}
+
+func (sm *StorageMinerAPI) MarketSetRetrievalAsk(ctx context.Context, rask *retrievalmarket.Ask) error {
+	sm.RetrievalProvider.SetAsk(rask)
+	return nil
+}
+
+func (sm *StorageMinerAPI) MarketGetRetrievalAsk(ctx context.Context) (*retrievalmarket.Ask, error) {
+	return sm.RetrievalProvider.GetAsk(), nil
+}
+
+func (sm *StorageMinerAPI) DealsSetAcceptingStorageDeals(ctx context.Context, b bool) error {
+	v := int32(0)
+	if b {
+		v = 1
+	}
+
+	atomic.StoreInt32(&sm.acceptingStorageDeals, v)
+	return nil
+}
+
+// AcceptingStorageDeals reports whether the miner currently considers new
+// storage deal proposals. It is consulted by the deal-acceptance hook ahead
+// of the blocklist/allowlist/price-override policy.
+func (sm *StorageMinerAPI) AcceptingStorageDeals() bool {
+	return atomic.LoadInt32(&sm.acceptingStorageDeals) == 1
+}
+
+func (sm *StorageMinerAPI) DealsImportData(ctx context.Context, dealProposalCid cid.Cid, file string) error {
+	fi, err := os.Open(file)
+	if err != nil {
+		return xerrors.Errorf("opening import file %q: %w", file, err)
+	}
+	defer fi.Close() //nolint:errcheck
+
+	return sm.StorageProvider.ImportDataForDeal(ctx, dealProposalCid, fi)
+}
+
+func (sm *StorageMinerAPI) MarketListIncompleteDeals(ctx context.Context) ([]storagemarket.MinerDeal, error) {
+	return sm.StorageProvider.ListLocalDeals()
+}
+
+func (sm *StorageMinerAPI) MarketListDataTransfers(ctx context.Context) ([]api.DataTransferChannel, error) {
+	return sm.Transfers.List(ctx)
+}
+
+func (sm *StorageMinerAPI) MarketDataTransferUpdates(ctx context.Context) (<-chan api.DataTransferChannel, error) {
+	return sm.Transfers.Subscribe(ctx), nil
+}
+
+func (sm *StorageMinerAPI) MarketRestartDataTransfer(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error {
+	return sm.Transfers.Restart(ctx, transferID, other)
+}
+
+func (sm *StorageMinerAPI) MarketCancelDataTransfer(ctx context.Context, transferID datatransfer.TransferID, other peer.ID) error {
+	return sm.Transfers.Cancel(ctx, transferID, other)
+}
+
+func (sm *StorageMinerAPI) MarketSetPieceCidBlocklist(ctx context.Context, blocklist []cid.Cid) error {
+	return sm.DealFilter.SetPieceCidBlocklist(ctx, blocklist)
+}
+
+func (sm *StorageMinerAPI) MarketSetClientAllowlist(ctx context.Context, allowlist []peer.ID) error {
+	return sm.DealFilter.SetClientAllowlist(ctx, allowlist)
+}
+
+func (sm *StorageMinerAPI) MarketSetClientPriceOverride(ctx context.Context, client peer.ID, price types.BigInt) error {
+	return sm.DealFilter.SetClientPriceOverride(ctx, client, price)
+}
+
+func (sm *StorageMinerAPI) MarketGetDealUpdates(ctx context.Context, proposalCid cid.Cid) ([]api.MarketDealEvent, error) {
+	return sm.DealLog.History(proposalCid), nil
+}
+
+func (sm *StorageMinerAPI) MarketSubscribeDealUpdates(ctx context.Context) (<-chan api.MarketDealEvent, error) {
+	return sm.DealLog.Subscribe(ctx), nil
+}